@@ -0,0 +1,117 @@
+package cachemap
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+)
+
+type opKind int
+
+const (
+	opPut opKind = iota
+	opDelete
+)
+
+type batchOp[K comparable, V any] struct {
+	Kind  opKind
+	Key   K
+	Value V
+}
+
+// Batch accumulates Put and Delete operations in memory so they can be
+// committed to a CacheMap atomically, replayed onto several cache instances
+// (e.g. for replication), or serialized for write-behind propagation to
+// peer caches.
+type Batch[K comparable, V any] struct {
+	ops []batchOp[K, V]
+}
+
+// NewBatch returns an empty Batch.
+func NewBatch[K comparable, V any]() *Batch[K, V] {
+	return &Batch[K, V]{}
+}
+
+// Put stages setting key to value.
+func (b *Batch[K, V]) Put(key K, value V) {
+	b.ops = append(b.ops, batchOp[K, V]{Kind: opPut, Key: key, Value: value})
+}
+
+// Delete stages removing key.
+func (b *Batch[K, V]) Delete(key K) {
+	b.ops = append(b.ops, batchOp[K, V]{Kind: opDelete, Key: key})
+}
+
+// Len returns the number of staged operations.
+func (b *Batch[K, V]) Len() int {
+	return len(b.ops)
+}
+
+// BatchReplayer receives the operations staged in a Batch, in order.
+type BatchReplayer[K comparable, V any] interface {
+	Put(K, V)
+	Delete(K)
+}
+
+// Replay applies every staged operation, in order, to r. It validates all
+// operations before applying any of them, so a corrupt batch (e.g. decoded
+// from a peer running a different version) is rejected without partially
+// applying to r.
+func (b *Batch[K, V]) Replay(r BatchReplayer[K, V]) error {
+	if b == nil {
+		return nil
+	}
+	for _, op := range b.ops {
+		if op.Kind != opPut && op.Kind != opDelete {
+			return fmt.Errorf("cachemap: unknown batch op %d", op.Kind)
+		}
+	}
+	for _, op := range b.ops {
+		if op.Kind == opPut {
+			r.Put(op.Key, op.Value)
+		} else {
+			r.Delete(op.Key)
+		}
+	}
+	return nil
+}
+
+func (b *Batch[K, V]) GobEncode() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := gob.NewEncoder(buf).Encode(b.ops); err != nil {
+		return nil, fmt.Errorf("gob encode error: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (b *Batch[K, V]) GobDecode(data []byte) error {
+	var ops []batchOp[K, V]
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&ops); err != nil {
+		return fmt.Errorf("gob decode error: %w", err)
+	}
+	b.ops = ops
+	return nil
+}
+
+// cacheMapReplayer adapts a CacheMap, already held under Lock, to
+// BatchReplayer.
+type cacheMapReplayer[K comparable, V any] struct {
+	cm *CacheMap[K, V]
+}
+
+func (r cacheMapReplayer[K, V]) Put(key K, value V) {
+	r.cm.SetWithoutLock(key, value)
+}
+
+func (r cacheMapReplayer[K, V]) Delete(key K) {
+	r.cm.DeleteWithoutLock(key)
+}
+
+// Apply commits every operation staged in b under a single Lock, so callers
+// no longer need to take Lock themselves and call many SetWithoutLock
+// operations to stage a consistent update.
+func (cm *CacheMap[K, V]) Apply(b *Batch[K, V]) error {
+	cm.Lock()
+	defer cm.Unlock()
+	return b.Replay(cacheMapReplayer[K, V]{cm: cm})
+}
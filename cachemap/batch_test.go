@@ -0,0 +1,93 @@
+package cachemap
+
+import "testing"
+
+func TestBatchApply(t *testing.T) {
+	cm := NewCacheMap[string, int]()
+	cm.Set("a", 1)
+	cm.Set("b", 2)
+
+	b := NewBatch[string, int]()
+	b.Put("a", 10)
+	b.Put("c", 3)
+	b.Delete("b")
+
+	if err := cm.Apply(b); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+
+	if v, ok := cm.Get("a"); !ok || v != 10 {
+		t.Fatalf("Get(a) = %v, %v; want 10, true", v, ok)
+	}
+	if v, ok := cm.Get("c"); !ok || v != 3 {
+		t.Fatalf("Get(c) = %v, %v; want 3, true", v, ok)
+	}
+	if _, ok := cm.Get("b"); ok {
+		t.Fatalf("Get(b) after Delete: want absent")
+	}
+}
+
+// recordingReplayer is a BatchReplayer that just records the calls it
+// receives, used to exercise Replay independently of a CacheMap.
+type recordingReplayer struct {
+	puts    map[string]int
+	deletes []string
+}
+
+func (r *recordingReplayer) Put(key string, value int) {
+	r.puts[key] = value
+}
+
+func (r *recordingReplayer) Delete(key string) {
+	r.deletes = append(r.deletes, key)
+}
+
+func TestBatchReplay(t *testing.T) {
+	b := NewBatch[string, int]()
+	b.Put("a", 1)
+	b.Delete("b")
+	b.Put("c", 3)
+
+	r := &recordingReplayer{puts: make(map[string]int)}
+	if err := b.Replay(r); err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+
+	if len(r.puts) != 2 || r.puts["a"] != 1 || r.puts["c"] != 3 {
+		t.Fatalf("puts = %v; want map[a:1 c:3]", r.puts)
+	}
+	if len(r.deletes) != 1 || r.deletes[0] != "b" {
+		t.Fatalf("deletes = %v; want [b]", r.deletes)
+	}
+}
+
+func TestBatchGobRoundTrip(t *testing.T) {
+	b := NewBatch[string, int]()
+	b.Put("a", 1)
+	b.Delete("b")
+	b.Put("c", 3)
+
+	data, err := b.GobEncode()
+	if err != nil {
+		t.Fatalf("GobEncode: %v", err)
+	}
+
+	var decoded Batch[string, int]
+	if err := decoded.GobDecode(data); err != nil {
+		t.Fatalf("GobDecode: %v", err)
+	}
+	if decoded.Len() != b.Len() {
+		t.Fatalf("decoded.Len() = %d; want %d", decoded.Len(), b.Len())
+	}
+
+	r := &recordingReplayer{puts: make(map[string]int)}
+	if err := decoded.Replay(r); err != nil {
+		t.Fatalf("Replay on decoded batch: %v", err)
+	}
+	if len(r.puts) != 2 || r.puts["a"] != 1 || r.puts["c"] != 3 {
+		t.Fatalf("puts = %v; want map[a:1 c:3]", r.puts)
+	}
+	if len(r.deletes) != 1 || r.deletes[0] != "b" {
+		t.Fatalf("deletes = %v; want [b]", r.deletes)
+	}
+}
@@ -2,67 +2,392 @@ package cachemap
 
 import (
 	"bytes"
+	"container/list"
+	"encoding/binary"
 	"encoding/gob"
 	"fmt"
-	"maps"
+	"hash/maphash"
 	"sync"
+	"time"
 )
 
-type CacheMap[K comparable, V any] struct {
-	m    map[K]V
+// shard is one stripe of the map, guarded by its own lock so that
+// operations on keys in other shards are never blocked by it.
+type shard[K comparable, V any] struct {
 	lock sync.RWMutex
+	m    map[K]entry[V]
+
+	// order and elems track recency for capacity-bounded shards; both are
+	// nil when the shard is unbounded. order.Front() is most recently used.
+	order    *list.List
+	elems    map[K]*list.Element
+	capacity int
+
+	// calls tracks in-flight GetOrLoad loaders, keyed by the key being
+	// loaded, so concurrent callers for the same key coalesce onto one call.
+	calls map[K]*call[V]
+}
+
+// touch marks key as most recently used. No-op on unbounded shards.
+func (s *shard[K, V]) touch(key K) {
+	if s.order == nil {
+		return
+	}
+	if el, ok := s.elems[key]; ok {
+		s.order.MoveToFront(el)
+		return
+	}
+	s.elems[key] = s.order.PushFront(key)
+}
+
+// untrack removes key from the recency list. No-op on unbounded shards.
+func (s *shard[K, V]) untrack(key K) {
+	if s.order == nil {
+		return
+	}
+	if el, ok := s.elems[key]; ok {
+		s.order.Remove(el)
+		delete(s.elems, key)
+	}
 }
 
+// setLocked stores value for key, expiring at expiry (the zero time.Time
+// means no expiry), and, on a capacity-bounded shard, evicts the least
+// recently used entry if the shard is now over capacity. The caller must
+// hold the shard's write lock.
+func (s *shard[K, V]) setLocked(key K, value V, expiry time.Time) (evictedKey K, evictedValue V, evicted bool) {
+	s.m[key] = entry[V]{Value: value, Expiry: expiry}
+	if s.capacity <= 0 {
+		return
+	}
+	s.touch(key)
+	if len(s.m) <= s.capacity {
+		return
+	}
+	back := s.order.Back()
+	if back == nil {
+		return
+	}
+	ek := back.Value.(K)
+	if e, ok := s.m[ek]; ok {
+		evictedKey, evictedValue, evicted = ek, e.Value, true
+		delete(s.m, ek)
+	}
+	s.order.Remove(back)
+	delete(s.elems, ek)
+	return
+}
+
+// entry is the value stored per key. A zero Expiry means the entry set via
+// Set never expires.
+type entry[V any] struct {
+	Value  V
+	Expiry time.Time
+}
+
+func (e entry[V]) expired(now time.Time) bool {
+	return !e.Expiry.IsZero() && now.After(e.Expiry)
+}
+
+type CacheMap[K comparable, V any] struct {
+	// initOnce lazily populates shards/mask/seed the first time a method is
+	// called on a zero-value CacheMap (e.g. one produced by gob decoding
+	// into `var cm CacheMap[K, V]`), so the zero value is usable the same
+	// way a zero-value map is.
+	initOnce sync.Once
+	shards   []*shard[K, V]
+	mask     uint64
+	seed     maphash.Seed
+
+	// defaultTTL is used by SetWithDefaultTTL; set via NewCacheMapWithTTL.
+	defaultTTL time.Duration
+
+	// evictionCallback, if set, is invoked with the key and value of every
+	// entry evicted to stay within a capacity-bounded shard's limit.
+	evictionCallback EvictionCallback[K, V]
+}
+
+// EvictionCallback is invoked when a capacity-bounded CacheMap evicts an
+// entry to make room for a new one.
+type EvictionCallback[K comparable, V any] func(K, V)
+
+// SetEvictionCallback registers cb to be called on every eviction from a
+// capacity-bounded CacheMap created via NewCacheMapWithCapacity.
+func (cm *CacheMap[K, V]) SetEvictionCallback(cb EvictionCallback[K, V]) {
+	cm.evictionCallback = cb
+}
+
+// NewCacheMap returns a CacheMap backed by a single shard, equivalent to the
+// previous single-mutex behavior.
 func NewCacheMap[K comparable, V any]() *CacheMap[K, V] {
-	cm := CacheMap[K, V]{m: make(map[K]V)}
-	return &cm
+	return NewCacheMapSharded[K, V](1)
+}
+
+// NewCacheMapSharded returns a CacheMap whose keys are distributed across
+// shards stripes, each guarded by its own sync.RWMutex, so Get/Set/Delete on
+// keys in different shards proceed in parallel. shards is rounded up to the
+// next power of two.
+func NewCacheMapSharded[K comparable, V any](shards int) *CacheMap[K, V] {
+	if shards < 1 {
+		shards = 1
+	}
+	shards = nextPow2(shards)
+
+	ss := make([]*shard[K, V], shards)
+	for i := range ss {
+		ss[i] = &shard[K, V]{m: make(map[K]entry[V])}
+	}
+	return &CacheMap[K, V]{
+		shards: ss,
+		mask:   uint64(shards - 1),
+		seed:   maphash.MakeSeed(),
+	}
+}
+
+func nextPow2(n int) int {
+	p := 1
+	for p < n {
+		p <<= 1
+	}
+	return p
 }
 
+// ensureInit makes a zero-value CacheMap (shards == nil) usable by giving it
+// the same single-shard layout NewCacheMap would have produced. It is a
+// no-op on a CacheMap built by any constructor. Every entry point that
+// touches cm.shards calls this first, so Lock/RLock never lock a shard
+// slice that a concurrent call is about to replace out from under them.
+func (cm *CacheMap[K, V]) ensureInit() {
+	cm.initOnce.Do(func() {
+		if cm.shards != nil {
+			return
+		}
+		cm.shards = []*shard[K, V]{{m: make(map[K]entry[V])}}
+		cm.mask = 0
+		cm.seed = maphash.MakeSeed()
+	})
+}
+
+// shardFor returns the shard responsible for key.
+func (cm *CacheMap[K, V]) shardFor(key K) *shard[K, V] {
+	cm.ensureInit()
+	var h maphash.Hash
+	h.SetSeed(cm.seed)
+	writeHash(&h, key)
+	return cm.shards[h.Sum64()&cm.mask]
+}
+
+// writeHash feeds key's bytes into h directly for the common key kinds,
+// avoiding the reflection and allocation that fmt.Fprintf("%v", key) would
+// do on every Get/Set/Delete. Arbitrary struct keys fall back to that
+// formatting, since there's no generic way to get their bytes directly.
+func writeHash[K comparable](h *maphash.Hash, key K) {
+	switch k := any(key).(type) {
+	case string:
+		h.WriteString(k)
+	case []byte:
+		h.Write(k)
+	case int:
+		writeUint64(h, uint64(k))
+	case int8:
+		h.WriteByte(byte(k))
+	case int16:
+		writeUint16(h, uint16(k))
+	case int32:
+		writeUint32(h, uint32(k))
+	case int64:
+		writeUint64(h, uint64(k))
+	case uint:
+		writeUint64(h, uint64(k))
+	case uint8:
+		h.WriteByte(k)
+	case uint16:
+		writeUint16(h, k)
+	case uint32:
+		writeUint32(h, k)
+	case uint64:
+		writeUint64(h, k)
+	case uintptr:
+		writeUint64(h, uint64(k))
+	case bool:
+		if k {
+			h.WriteByte(1)
+		} else {
+			h.WriteByte(0)
+		}
+	default:
+		fmt.Fprintf(h, "%v", key)
+	}
+}
+
+func writeUint16(h *maphash.Hash, v uint16) {
+	var buf [2]byte
+	binary.LittleEndian.PutUint16(buf[:], v)
+	h.Write(buf[:])
+}
+
+func writeUint32(h *maphash.Hash, v uint32) {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], v)
+	h.Write(buf[:])
+}
+
+func writeUint64(h *maphash.Hash, v uint64) {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], v)
+	h.Write(buf[:])
+}
+
+// Lock locks every shard, in stable order, giving exclusive access to the
+// whole map. Hold this for the duration of a sequence of *WithoutLock calls
+// that must be applied atomically.
 func (cm *CacheMap[K, V]) Lock() {
-	cm.lock.Lock()
+	cm.ensureInit()
+	for _, s := range cm.shards {
+		s.lock.Lock()
+	}
 }
 
 func (cm *CacheMap[K, V]) Unlock() {
-	cm.lock.Unlock()
+	for i := len(cm.shards) - 1; i >= 0; i-- {
+		cm.shards[i].lock.Unlock()
+	}
 }
 
+// RLock locks every shard, in stable order, giving shared access to the
+// whole map.
 func (cm *CacheMap[K, V]) RLock() {
-	cm.lock.RLock()
+	cm.ensureInit()
+	for _, s := range cm.shards {
+		s.lock.RLock()
+	}
 }
 
 func (cm *CacheMap[K, V]) RUnlock() {
-	cm.lock.RUnlock()
+	for i := len(cm.shards) - 1; i >= 0; i-- {
+		cm.shards[i].lock.RUnlock()
+	}
+}
+
+// LockKey locks only the shard holding k and returns a closure that unlocks
+// it, letting a caller do a read-modify-write on a single key without
+// blocking the rest of the map.
+func (cm *CacheMap[K, V]) LockKey(k K) func() {
+	s := cm.shardFor(k)
+	s.lock.Lock()
+	return s.lock.Unlock
 }
 
 func (cm *CacheMap[K, V]) Delete(key K) {
-	cm.Lock()
-	defer cm.Unlock()
-	cm.DeleteWithoutLock(key)
+	s := cm.shardFor(key)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.m, key)
+	s.untrack(key)
 }
 
+// DeleteWithoutLock deletes key without taking a lock. The caller must
+// already hold Lock, or hold the shard for key via LockKey.
 func (cm *CacheMap[K, V]) DeleteWithoutLock(key K) {
-	delete(cm.m, key)
+	s := cm.shardFor(key)
+	delete(s.m, key)
+	s.untrack(key)
 }
 
+// Get returns the value for key. An entry whose TTL has elapsed is treated
+// as absent and is lazily deleted under the shard's write lock. On a
+// capacity-bounded CacheMap, a hit also marks key as most recently used.
 func (cm *CacheMap[K, V]) Get(key K) (V, bool) {
-	cm.RLock()
-	defer cm.RUnlock()
-	return cm.GetWithoutLock(key)
+	s := cm.shardFor(key)
+	if s.capacity > 0 {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		e, ok := s.m[key]
+		if !ok {
+			var zero V
+			return zero, false
+		}
+		if e.expired(time.Now()) {
+			delete(s.m, key)
+			s.untrack(key)
+			var zero V
+			return zero, false
+		}
+		s.touch(key)
+		return e.Value, true
+	}
+
+	s.lock.RLock()
+	e, ok := s.m[key]
+	s.lock.RUnlock()
+	if !ok || !e.expired(time.Now()) {
+		return e.Value, ok
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if e, ok = s.m[key]; ok && e.expired(time.Now()) {
+		delete(s.m, key)
+	}
+	var zero V
+	return zero, false
 }
 
+// GetWithoutLock reads key without taking a lock, deleting it in place if
+// its TTL has elapsed. It does not update recency on a capacity-bounded
+// CacheMap; use Get for that. The caller must already hold Lock/RLock, or
+// hold the shard for key via LockKey.
 func (cm *CacheMap[K, V]) GetWithoutLock(key K) (value V, ok bool) {
-	value, ok = cm.m[key]
-	return
+	s := cm.shardFor(key)
+	e, found := s.m[key]
+	if !found {
+		return
+	}
+	if e.expired(time.Now()) {
+		delete(s.m, key)
+		s.untrack(key)
+		return
+	}
+	return e.Value, true
+}
+
+// Peek returns the value for key without marking it as recently used, so it
+// does not perturb eviction order on a capacity-bounded CacheMap.
+func (cm *CacheMap[K, V]) Peek(key K) (V, bool) {
+	s := cm.shardFor(key)
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	e, ok := s.m[key]
+	if !ok || e.expired(time.Now()) {
+		var zero V
+		return zero, false
+	}
+	return e.Value, true
 }
 
+// Set stores value for key. Entries set this way never expire. On a
+// capacity-bounded CacheMap, this may evict the least recently used entry,
+// invoking the registered EvictionCallback.
 func (cm *CacheMap[K, V]) Set(key K, value V) {
-	cm.Lock()
-	defer cm.Unlock()
-	cm.SetWithoutLock(key, value)
+	s := cm.shardFor(key)
+	s.lock.Lock()
+	evictedKey, evictedValue, evicted := s.setLocked(key, value, time.Time{})
+	s.lock.Unlock()
+	if evicted && cm.evictionCallback != nil {
+		cm.evictionCallback(evictedKey, evictedValue)
+	}
 }
 
+// SetWithoutLock writes key without taking a lock. The caller must already
+// hold Lock, or hold the shard for key via LockKey. Entries set this way
+// never expire. On a capacity-bounded CacheMap, this may evict the least
+// recently used entry, invoking the registered EvictionCallback inline.
 func (cm *CacheMap[K, V]) SetWithoutLock(key K, value V) {
-	cm.m[key] = value
+	s := cm.shardFor(key)
+	evictedKey, evictedValue, evicted := s.setLocked(key, value, time.Time{})
+	if evicted && cm.evictionCallback != nil {
+		cm.evictionCallback(evictedKey, evictedValue)
+	}
 }
 
 func (cm *CacheMap[K, V]) Len() int {
@@ -72,7 +397,11 @@ func (cm *CacheMap[K, V]) Len() int {
 }
 
 func (cm *CacheMap[K, V]) LenWithoutLock() int {
-	return len(cm.m)
+	n := 0
+	for _, s := range cm.shards {
+		n += len(s.m)
+	}
+	return n
 }
 
 func (cm *CacheMap[K, V]) Clear() {
@@ -82,9 +411,16 @@ func (cm *CacheMap[K, V]) Clear() {
 }
 
 func (cm *CacheMap[K, V]) ClearWithoutLock() {
-	cm.m = make(map[K]V)
+	for _, s := range cm.shards {
+		s.m = make(map[K]entry[V])
+		if s.order != nil {
+			s.order = list.New()
+			s.elems = make(map[K]*list.Element)
+		}
+	}
 }
 
+// Export returns a snapshot of the map's current, non-expired values.
 func (cm *CacheMap[K, V]) Export() map[K]V {
 	cm.RLock()
 	defer cm.RUnlock()
@@ -92,7 +428,16 @@ func (cm *CacheMap[K, V]) Export() map[K]V {
 }
 
 func (cm *CacheMap[K, V]) ExportWithoutLock() map[K]V {
-	return maps.Collect(maps.All(cm.m))
+	now := time.Now()
+	out := make(map[K]V)
+	for _, s := range cm.shards {
+		for k, e := range s.m {
+			if !e.expired(now) {
+				out[k] = e.Value
+			}
+		}
+	}
+	return out
 }
 
 func (cm *CacheMap[K, V]) Import(m map[K]V) {
@@ -101,31 +446,76 @@ func (cm *CacheMap[K, V]) Import(m map[K]V) {
 	cm.ImportWithoutLock(m)
 }
 
+// ImportWithoutLock replaces the contents of the map with m, redistributing
+// entries across shards. The caller must already hold Lock.
 func (cm *CacheMap[K, V]) ImportWithoutLock(m map[K]V) {
-	cm.m = m
+	cm.ClearWithoutLock()
+	for k, v := range m {
+		cm.SetWithoutLock(k, v)
+	}
+}
+
+// kvEntry pairs a key with its stored entry, used to serialize the map as
+// an ordered sequence rather than an unordered gob map.
+type kvEntry[K comparable, V any] struct {
+	Key   K
+	Entry entry[V]
 }
 
+// GobEncode encodes the map's entries, including their expiry timestamps,
+// in LRU order on capacity-bounded shards, so a decoded cache resumes with
+// correct remaining lifetimes and recency.
 func (cm *CacheMap[K, V]) GobEncode() ([]byte, error) {
 	cm.RLock()
 	defer cm.RUnlock()
 	// cm.mをGobエンコードして返す
 	buf := new(bytes.Buffer)
 	encoder := gob.NewEncoder(buf)
-	err := encoder.Encode(cm.m)
+	err := encoder.Encode(cm.exportOrderedWithoutLock())
 	if err != nil {
 		return nil, fmt.Errorf("gob encode error: %w", err)
 	}
 	return buf.Bytes(), nil
 }
 
+// exportOrderedWithoutLock returns every entry, most recently used first on
+// capacity-bounded shards.
+func (cm *CacheMap[K, V]) exportOrderedWithoutLock() []kvEntry[K, V] {
+	var out []kvEntry[K, V]
+	for _, s := range cm.shards {
+		if s.order == nil {
+			for k, e := range s.m {
+				out = append(out, kvEntry[K, V]{Key: k, Entry: e})
+			}
+			continue
+		}
+		for el := s.order.Front(); el != nil; el = el.Next() {
+			k := el.Value.(K)
+			if e, ok := s.m[k]; ok {
+				out = append(out, kvEntry[K, V]{Key: k, Entry: e})
+			}
+		}
+	}
+	return out
+}
+
 func (cm *CacheMap[K, V]) GobDecode(data []byte) error {
 	cm.Lock()
 	defer cm.Unlock()
 	// dataをGobデコードしてcm.mにセットする
 	buf := bytes.NewBuffer(data)
 	decoder := gob.NewDecoder(buf)
-	if err := decoder.Decode(&cm.m); err != nil {
+	var pairs []kvEntry[K, V]
+	if err := decoder.Decode(&pairs); err != nil {
 		return fmt.Errorf("gob decode error: %w", err)
 	}
+	cm.ClearWithoutLock()
+	for _, p := range pairs {
+		s := cm.shardFor(p.Key)
+		s.m[p.Key] = p.Entry
+		if s.order != nil {
+			s.elems[p.Key] = s.order.PushBack(p.Key)
+		}
+	}
 	return nil
 }
@@ -0,0 +1,95 @@
+package cachemap
+
+import (
+	"bytes"
+	"encoding/gob"
+	"sync"
+	"testing"
+)
+
+func TestCacheMapBasic(t *testing.T) {
+	cm := NewCacheMapSharded[string, int](4)
+	cm.Set("a", 1)
+	cm.Set("b", 2)
+
+	if v, ok := cm.Get("a"); !ok || v != 1 {
+		t.Fatalf("Get(a) = %v, %v; want 1, true", v, ok)
+	}
+
+	cm.Delete("a")
+	if _, ok := cm.Get("a"); ok {
+		t.Fatalf("Get(a) after Delete: want absent")
+	}
+}
+
+func TestCacheMapConcurrentDistinctKeys(t *testing.T) {
+	cm := NewCacheMapSharded[int, int](16)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			cm.Set(i, i*i)
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < 100; i++ {
+		if v, ok := cm.Get(i); !ok || v != i*i {
+			t.Fatalf("Get(%d) = %v, %v; want %d, true", i, v, ok, i*i)
+		}
+	}
+}
+
+// TestCacheMapZeroValueGobDecode reproduces a `var cm CacheMap[K, V]; gob
+// decode into &cm` flow directly via GobDecode.
+func TestCacheMapZeroValueGobDecode(t *testing.T) {
+	src := NewCacheMap[string, int]()
+	src.Set("a", 1)
+	src.Set("b", 2)
+	data, err := src.GobEncode()
+	if err != nil {
+		t.Fatalf("GobEncode: %v", err)
+	}
+
+	var dst CacheMap[string, int]
+	if err := dst.GobDecode(data); err != nil {
+		t.Fatalf("GobDecode into zero-value CacheMap: %v", err)
+	}
+	if v, ok := dst.Get("a"); !ok || v != 1 {
+		t.Fatalf("Get(a) = %v, %v; want 1, true", v, ok)
+	}
+	if v, ok := dst.Get("b"); !ok || v != 2 {
+		t.Fatalf("Get(b) = %v, %v; want 2, true", v, ok)
+	}
+}
+
+// TestCacheMapZeroValueGobUnmarshal goes through the full encoding/gob
+// package, the normal way a CacheMap would be deserialized.
+func TestCacheMapZeroValueGobUnmarshal(t *testing.T) {
+	src := NewCacheMap[string, int]()
+	src.Set("a", 1)
+
+	buf := new(bytes.Buffer)
+	if err := gob.NewEncoder(buf).Encode(src); err != nil {
+		t.Fatalf("gob encode: %v", err)
+	}
+
+	var dst CacheMap[string, int]
+	if err := gob.NewDecoder(buf).Decode(&dst); err != nil {
+		t.Fatalf("gob decode into zero-value CacheMap: %v", err)
+	}
+	if v, ok := dst.Get("a"); !ok || v != 1 {
+		t.Fatalf("Get(a) = %v, %v; want 1, true", v, ok)
+	}
+}
+
+func TestCacheMapZeroValueImport(t *testing.T) {
+	var cm CacheMap[string, int]
+	cm.Import(map[string]int{"a": 1})
+
+	if v, ok := cm.Get("a"); !ok || v != 1 {
+		t.Fatalf("Get(a) = %v, %v; want 1, true", v, ok)
+	}
+}
@@ -0,0 +1,143 @@
+package cachemap
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/golang/snappy"
+)
+
+// Codec encodes and decodes a CacheMap's contents for persistence or
+// transport. Encode/Decode operate on a snapshot of the map, not the
+// CacheMap itself, so codecs stay reusable across instances.
+type Codec[K comparable, V any] interface {
+	Encode(map[K]V) ([]byte, error)
+	Decode([]byte) (map[K]V, error)
+}
+
+// GobCodec encodes the map snapshot as a plain gob-encoded map[K]V. This is
+// not the payload GobEncode/GobDecode use, which also carries expiry
+// timestamps and recency order, so the two cannot decode each other's output.
+type GobCodec[K comparable, V any] struct{}
+
+func (GobCodec[K, V]) Encode(m map[K]V) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := gob.NewEncoder(buf).Encode(m); err != nil {
+		return nil, fmt.Errorf("gob encode error: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (GobCodec[K, V]) Decode(data []byte) (map[K]V, error) {
+	var m map[K]V
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&m); err != nil {
+		return nil, fmt.Errorf("gob decode error: %w", err)
+	}
+	return m, nil
+}
+
+// JSONCodec encodes with encoding/json. K must be a type json supports as a
+// map key (a string, an integer, or one implementing encoding.TextMarshaler).
+type JSONCodec[K comparable, V any] struct{}
+
+func (JSONCodec[K, V]) Encode(m map[K]V) ([]byte, error) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("json encode error: %w", err)
+	}
+	return data, nil
+}
+
+func (JSONCodec[K, V]) Decode(data []byte) (map[K]V, error) {
+	var m map[K]V
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("json decode error: %w", err)
+	}
+	return m, nil
+}
+
+// snappyCodec wraps another codec with Snappy compression, useful for
+// caches dominated by repetitive string values.
+type snappyCodec[K comparable, V any] struct {
+	inner Codec[K, V]
+}
+
+// SnappyCodec returns a Codec that compresses inner's encoded output with
+// Snappy, and decompresses before handing bytes back to inner.
+func SnappyCodec[K comparable, V any](inner Codec[K, V]) Codec[K, V] {
+	return snappyCodec[K, V]{inner: inner}
+}
+
+func (c snappyCodec[K, V]) Encode(m map[K]V) ([]byte, error) {
+	raw, err := c.inner.Encode(m)
+	if err != nil {
+		return nil, err
+	}
+	return snappy.Encode(nil, raw), nil
+}
+
+func (c snappyCodec[K, V]) Decode(data []byte) (map[K]V, error) {
+	raw, err := snappy.Decode(nil, data)
+	if err != nil {
+		return nil, fmt.Errorf("snappy decode error: %w", err)
+	}
+	return c.inner.Decode(raw)
+}
+
+// MarshalWith encodes a snapshot of the map's current values using codec.
+func (cm *CacheMap[K, V]) MarshalWith(codec Codec[K, V]) ([]byte, error) {
+	return codec.Encode(cm.Export())
+}
+
+// UnmarshalWith decodes data with codec and replaces the map's contents
+// with the result.
+func (cm *CacheMap[K, V]) UnmarshalWith(codec Codec[K, V], data []byte) error {
+	m, err := codec.Decode(data)
+	if err != nil {
+		return err
+	}
+	cm.Import(m)
+	return nil
+}
+
+// SaveToFile encodes the map with codec and writes it to path, via a
+// temp-file-plus-rename so a crash mid-write cannot corrupt path.
+func (cm *CacheMap[K, V]) SaveToFile(path string, codec Codec[K, V]) error {
+	data, err := cm.MarshalWith(codec)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close temp file: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("rename temp file: %w", err)
+	}
+	return nil
+}
+
+// LoadFromFile reads path and decodes it with codec into the map, replacing
+// its current contents.
+func (cm *CacheMap[K, V]) LoadFromFile(path string, codec Codec[K, V]) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read file: %w", err)
+	}
+	return cm.UnmarshalWith(codec, data)
+}
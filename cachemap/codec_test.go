@@ -0,0 +1,110 @@
+package cachemap
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGobCodecRoundTrip(t *testing.T) {
+	cm := NewCacheMap[string, int]()
+	cm.Set("a", 1)
+	cm.Set("b", 2)
+
+	data, err := cm.MarshalWith(GobCodec[string, int]{})
+	if err != nil {
+		t.Fatalf("MarshalWith: %v", err)
+	}
+
+	dst := NewCacheMap[string, int]()
+	if err := dst.UnmarshalWith(GobCodec[string, int]{}, data); err != nil {
+		t.Fatalf("UnmarshalWith: %v", err)
+	}
+	if v, ok := dst.Get("a"); !ok || v != 1 {
+		t.Fatalf("Get(a) = %v, %v; want 1, true", v, ok)
+	}
+	if v, ok := dst.Get("b"); !ok || v != 2 {
+		t.Fatalf("Get(b) = %v, %v; want 2, true", v, ok)
+	}
+}
+
+func TestJSONCodecRoundTrip(t *testing.T) {
+	cm := NewCacheMap[string, int]()
+	cm.Set("a", 1)
+	cm.Set("b", 2)
+
+	data, err := cm.MarshalWith(JSONCodec[string, int]{})
+	if err != nil {
+		t.Fatalf("MarshalWith: %v", err)
+	}
+
+	dst := NewCacheMap[string, int]()
+	if err := dst.UnmarshalWith(JSONCodec[string, int]{}, data); err != nil {
+		t.Fatalf("UnmarshalWith: %v", err)
+	}
+	if v, ok := dst.Get("a"); !ok || v != 1 {
+		t.Fatalf("Get(a) = %v, %v; want 1, true", v, ok)
+	}
+	if v, ok := dst.Get("b"); !ok || v != 2 {
+		t.Fatalf("Get(b) = %v, %v; want 2, true", v, ok)
+	}
+}
+
+// TestSnappyCodecRoundTrip wraps GobCodec with SnappyCodec to verify the
+// compress/decompress layer is transparent to the inner codec.
+func TestSnappyCodecRoundTrip(t *testing.T) {
+	cm := NewCacheMap[string, int]()
+	cm.Set("a", 1)
+	cm.Set("b", 2)
+
+	codec := SnappyCodec[string, int](GobCodec[string, int]{})
+	data, err := cm.MarshalWith(codec)
+	if err != nil {
+		t.Fatalf("MarshalWith: %v", err)
+	}
+
+	dst := NewCacheMap[string, int]()
+	if err := dst.UnmarshalWith(codec, data); err != nil {
+		t.Fatalf("UnmarshalWith: %v", err)
+	}
+	if v, ok := dst.Get("a"); !ok || v != 1 {
+		t.Fatalf("Get(a) = %v, %v; want 1, true", v, ok)
+	}
+	if v, ok := dst.Get("b"); !ok || v != 2 {
+		t.Fatalf("Get(b) = %v, %v; want 2, true", v, ok)
+	}
+}
+
+// TestSaveAndLoadFromFile checks the temp-file-plus-rename persistence path
+// round-trips and leaves no stray temp file behind in the target directory.
+func TestSaveAndLoadFromFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "cache.dat")
+
+	cm := NewCacheMap[string, int]()
+	cm.Set("a", 1)
+	cm.Set("b", 2)
+
+	if err := cm.SaveToFile(path, GobCodec[string, int]{}); err != nil {
+		t.Fatalf("SaveToFile: %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "cache.dat" {
+		t.Fatalf("dir entries = %v; want only cache.dat (no stray temp file)", entries)
+	}
+
+	dst := NewCacheMap[string, int]()
+	if err := dst.LoadFromFile(path, GobCodec[string, int]{}); err != nil {
+		t.Fatalf("LoadFromFile: %v", err)
+	}
+	if v, ok := dst.Get("a"); !ok || v != 1 {
+		t.Fatalf("Get(a) = %v, %v; want 1, true", v, ok)
+	}
+	if v, ok := dst.Get("b"); !ok || v != 2 {
+		t.Fatalf("Get(b) = %v, %v; want 2, true", v, ok)
+	}
+}
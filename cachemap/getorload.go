@@ -0,0 +1,67 @@
+package cachemap
+
+import (
+	"sync"
+	"time"
+)
+
+// call represents a loader running for a single key, shared by every
+// concurrent caller waiting on the same key.
+type call[V any] struct {
+	wg  sync.WaitGroup
+	val V
+	err error
+}
+
+// GetOrLoad returns the cached value for key, or, on a miss, invokes loader
+// exactly once and returns its result to every concurrent caller for that
+// key. The loader runs without holding the map lock, so it does not block
+// operations on other keys while in flight.
+func (cm *CacheMap[K, V]) GetOrLoad(key K, loader func(K) (V, error)) (V, error) {
+	if v, ok := cm.Get(key); ok {
+		return v, nil
+	}
+
+	s := cm.shardFor(key)
+	s.lock.Lock()
+	if e, ok := s.m[key]; ok && !e.expired(time.Now()) {
+		if s.capacity > 0 {
+			s.touch(key)
+		}
+		s.lock.Unlock()
+		return e.Value, nil
+	}
+	if c, ok := s.calls[key]; ok {
+		s.lock.Unlock()
+		c.wg.Wait()
+		return c.val, c.err
+	}
+
+	c := &call[V]{}
+	c.wg.Add(1)
+	if s.calls == nil {
+		s.calls = make(map[K]*call[V])
+	}
+	s.calls[key] = c
+	s.lock.Unlock()
+
+	val, err := loader(key)
+
+	s.lock.Lock()
+	var evictedKey K
+	var evictedValue V
+	evicted := false
+	if err == nil {
+		evictedKey, evictedValue, evicted = s.setLocked(key, val, time.Time{})
+	}
+	c.val, c.err = val, err
+	delete(s.calls, key)
+	s.lock.Unlock()
+	c.wg.Done()
+
+	if evicted && cm.evictionCallback != nil {
+		cm.evictionCallback(evictedKey, evictedValue)
+	}
+
+	return val, err
+}
@@ -0,0 +1,67 @@
+package cachemap
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestCacheMapGetOrLoadCoalescesConcurrentCallers holds the loader open on a
+// channel so every concurrent caller for the same key necessarily overlaps
+// with it, then asserts the loader only ran once.
+func TestCacheMapGetOrLoadCoalescesConcurrentCallers(t *testing.T) {
+	cm := NewCacheMap[string, int]()
+	var calls int32
+	release := make(chan struct{})
+
+	const n = 50
+	results := make([]int, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			v, err := cm.GetOrLoad("k", func(string) (int, error) {
+				atomic.AddInt32(&calls, 1)
+				<-release
+				return 42, nil
+			})
+			if err != nil {
+				t.Errorf("GetOrLoad: %v", err)
+			}
+			results[i] = v
+		}(i)
+	}
+
+	time.Sleep(50 * time.Millisecond) // let callers pile up behind the in-flight loader
+	close(release)
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("loader invoked %d times; want 1 (GetOrLoad must coalesce concurrent callers)", got)
+	}
+	for i, v := range results {
+		if v != 42 {
+			t.Fatalf("results[%d] = %d; want 42", i, v)
+		}
+	}
+}
+
+func TestCacheMapGetOrLoadCachesResult(t *testing.T) {
+	cm := NewCacheMap[string, int]()
+	calls := 0
+	loader := func(string) (int, error) {
+		calls++
+		return 7, nil
+	}
+
+	for i := 0; i < 3; i++ {
+		if v, err := cm.GetOrLoad("k", loader); err != nil || v != 7 {
+			t.Fatalf("GetOrLoad call %d = %v, %v; want 7, nil", i, v, err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("loader called %d times; want 1", calls)
+	}
+}
@@ -0,0 +1,16 @@
+package cachemap
+
+import "container/list"
+
+// NewCacheMapWithCapacity returns a CacheMap that holds at most max entries.
+// Once full, Set evicts the least recently used entry to make room,
+// invoking any callback registered via SetEvictionCallback.
+func NewCacheMapWithCapacity[K comparable, V any](max int) *CacheMap[K, V] {
+	cm := NewCacheMap[K, V]()
+	for _, s := range cm.shards {
+		s.capacity = max
+		s.order = list.New()
+		s.elems = make(map[K]*list.Element)
+	}
+	return cm
+}
@@ -0,0 +1,56 @@
+package cachemap
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCacheMapCapacityEvictsLRU(t *testing.T) {
+	var evicted []string
+	cm := NewCacheMapWithCapacity[string, int](2)
+	cm.SetEvictionCallback(func(k string, v int) {
+		evicted = append(evicted, k)
+	})
+
+	cm.Set("a", 1)
+	cm.Set("b", 2)
+	cm.Set("c", 3) // over capacity: evicts "a", the least recently used
+
+	if _, ok := cm.Get("a"); ok {
+		t.Fatalf("Get(a) after eviction: want absent")
+	}
+	if got := cm.Len(); got != 2 {
+		t.Fatalf("Len() = %d; want 2", got)
+	}
+	if len(evicted) != 1 || evicted[0] != "a" {
+		t.Fatalf("evicted = %v; want [a]", evicted)
+	}
+}
+
+// TestCacheMapCapacityRespectsSetWithTTL guards the invariant documented on
+// NewCacheMapWithCapacity: the map holds at most max entries, even when
+// entries are inserted via SetWithTTL rather than Set.
+func TestCacheMapCapacityRespectsSetWithTTL(t *testing.T) {
+	cm := NewCacheMapWithCapacity[string, int](2)
+	cm.SetWithTTL("a", 1, time.Hour)
+	cm.SetWithTTL("b", 2, time.Hour)
+	cm.SetWithTTL("c", 3, time.Hour)
+	cm.SetWithTTL("d", 4, time.Hour)
+
+	if got := cm.Len(); got != 2 {
+		t.Fatalf("Len() = %d; want 2 (capacity must hold under SetWithTTL too)", got)
+	}
+}
+
+func TestCacheMapPeekDoesNotPerturbRecency(t *testing.T) {
+	cm := NewCacheMapWithCapacity[string, int](2)
+	cm.Set("a", 1)
+	cm.Set("b", 2)
+
+	cm.Peek("a")
+	cm.Set("c", 3) // should still evict "a": Peek must not refresh recency
+
+	if _, ok := cm.Get("a"); ok {
+		t.Fatalf("Get(a) after eviction: want absent (Peek must not protect recency)")
+	}
+}
@@ -0,0 +1,74 @@
+package cachemap
+
+import (
+	"sync"
+	"time"
+)
+
+// NewCacheMapWithTTL returns a CacheMap whose SetWithDefaultTTL calls expire
+// entries after defaultTTL. Entries set via Set still never expire.
+func NewCacheMapWithTTL[K comparable, V any](defaultTTL time.Duration) *CacheMap[K, V] {
+	cm := NewCacheMap[K, V]()
+	cm.defaultTTL = defaultTTL
+	return cm
+}
+
+// SetWithTTL stores value for key, expiring it after ttl. Once the TTL has
+// elapsed, Get treats the entry as absent and lazily deletes it. On a
+// capacity-bounded CacheMap, this may evict the least recently used entry,
+// invoking the registered EvictionCallback, the same as Set.
+func (cm *CacheMap[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	s := cm.shardFor(key)
+	s.lock.Lock()
+	evictedKey, evictedValue, evicted := s.setLocked(key, value, time.Now().Add(ttl))
+	s.lock.Unlock()
+	if evicted && cm.evictionCallback != nil {
+		cm.evictionCallback(evictedKey, evictedValue)
+	}
+}
+
+// SetWithDefaultTTL stores value for key using the TTL configured via
+// NewCacheMapWithTTL.
+func (cm *CacheMap[K, V]) SetWithDefaultTTL(key K, value V) {
+	cm.SetWithTTL(key, value, cm.defaultTTL)
+}
+
+// StartJanitor spawns a goroutine that scans the map every interval and
+// removes expired entries, one shard at a time so the lock is only held
+// briefly between batches. Call the returned stop func to halt it.
+func (cm *CacheMap[K, V]) StartJanitor(interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				cm.evictExpired()
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
+
+func (cm *CacheMap[K, V]) evictExpired() {
+	cm.ensureInit()
+	now := time.Now()
+	for _, s := range cm.shards {
+		s.lock.Lock()
+		for k, e := range s.m {
+			if e.expired(now) {
+				delete(s.m, k)
+				s.untrack(k)
+			}
+		}
+		s.lock.Unlock()
+	}
+}
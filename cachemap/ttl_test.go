@@ -0,0 +1,68 @@
+package cachemap
+
+import (
+	"bytes"
+	"encoding/gob"
+	"testing"
+	"time"
+)
+
+func TestCacheMapGetTreatsExpiredAsAbsent(t *testing.T) {
+	cm := NewCacheMap[string, int]()
+	cm.SetWithTTL("a", 1, 10*time.Millisecond)
+
+	if v, ok := cm.Get("a"); !ok || v != 1 {
+		t.Fatalf("Get(a) before expiry = %v, %v; want 1, true", v, ok)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+
+	if _, ok := cm.Get("a"); ok {
+		t.Fatalf("Get(a) after expiry: want absent")
+	}
+}
+
+func TestCacheMapStartJanitorReapsExpiredEntries(t *testing.T) {
+	cm := NewCacheMap[string, int]()
+	cm.SetWithTTL("a", 1, 10*time.Millisecond)
+
+	stop := cm.StartJanitor(20 * time.Millisecond)
+	defer stop()
+
+	deadline := time.Now().Add(500 * time.Millisecond)
+	for cm.Len() != 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if got := cm.Len(); got != 0 {
+		t.Fatalf("Len() = %d after janitor ran; want 0", got)
+	}
+}
+
+// TestCacheMapGobRoundTripPreservesTTL checks a decoded entry keeps enough
+// of its remaining TTL to still be treated as live, but still expires once
+// the original deadline passes.
+func TestCacheMapGobRoundTripPreservesTTL(t *testing.T) {
+	src := NewCacheMap[string, int]()
+	src.SetWithTTL("a", 1, 200*time.Millisecond)
+
+	buf := new(bytes.Buffer)
+	if err := gob.NewEncoder(buf).Encode(src); err != nil {
+		t.Fatalf("gob encode: %v", err)
+	}
+
+	var dst CacheMap[string, int]
+	if err := gob.NewDecoder(buf).Decode(&dst); err != nil {
+		t.Fatalf("gob decode: %v", err)
+	}
+
+	if v, ok := dst.Get("a"); !ok || v != 1 {
+		t.Fatalf("Get(a) right after decode = %v, %v; want 1, true", v, ok)
+	}
+
+	time.Sleep(250 * time.Millisecond)
+
+	if _, ok := dst.Get("a"); ok {
+		t.Fatalf("Get(a) after original TTL elapsed: want absent")
+	}
+}